@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseTokens parses HOUSEKEEPER_TOKENS, a comma-separated list of
+// namespace=token pairs (e.g. "ns1=tok1,ns2=tok2"), into a map keyed by
+// namespace. An empty string yields an empty map, meaning no
+// per-namespace tokens are configured.
+func parseTokens(raw string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	if raw == "" {
+		return tokens, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid entry %q, expected namespace=token", pair)
+		}
+		tokens[parts[0]] = parts[1]
+	}
+
+	return tokens, nil
+}
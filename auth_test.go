@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTokensEmpty(t *testing.T) {
+	tokens, err := parseTokens("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("expected an empty map, got %v", tokens)
+	}
+}
+
+func TestParseTokensMultiple(t *testing.T) {
+	tokens, err := parseTokens("ns1=tok1,ns2=tok2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string]string{"ns1": "tok1", "ns2": "tok2"}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Fatalf("got %v, want %v", tokens, want)
+	}
+}
+
+func TestParseTokensInvalidEntry(t *testing.T) {
+	if _, err := parseTokens("ns1tok1"); err == nil {
+		t.Fatalf("expected an error for an entry missing '='")
+	}
+}
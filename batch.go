@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	nomad_api "github.com/hashicorp/nomad/api"
+	log "github.com/sirupsen/logrus"
+	str2duration "github.com/xhit/go-str2duration/v2"
+)
+
+const (
+	HousekeeperBatchRetention = "housekeeper/batch-retention"
+	HousekeeperKeepLast       = "housekeeper/keep-last"
+	HousekeeperChildRetention = "housekeeper/child-retention"
+)
+
+// batchRetention returns the retention window to apply to a batch job
+// before its most recent allocation makes it eligible for GC, and
+// whether batch GC is opted into at all for that job. Opt-in comes from
+// either the per-job housekeeper/batch-retention meta key or a matching
+// rule with BatchGC set; the housekeeper's configured BatchRetention
+// supplies the default window when the opt-in doesn't specify its own.
+func (h *Housekeeper) batchRetention(job *nomad_api.Job, rule *Rule) (time.Duration, bool) {
+	if value, ok := job.Meta[HousekeeperBatchRetention]; ok {
+		ttl, err := str2duration.ParseDuration(value)
+		if err != nil {
+			log.Warnf("could not interpret batch retention for job %s: %s", *job.ID, value)
+			return 0, false
+		}
+		return ttl, true
+	}
+
+	if rule != nil && rule.BatchGC {
+		if rule.BatchRetention == "" {
+			return h.config().BatchRetention, true
+		}
+		ttl, err := str2duration.ParseDuration(rule.BatchRetention)
+		if err != nil {
+			log.Warnf("could not interpret rule batch retention for job %s: %s", *job.ID, rule.BatchRetention)
+			return 0, false
+		}
+		return ttl, true
+	}
+
+	return 0, false
+}
+
+// keepLast returns how many completed instances of a periodic job's
+// children should be kept around, and whether periodic child GC is
+// enabled at all for that parent. The per-job housekeeper/keep-last
+// meta key overrides a matching rule.
+func keepLast(job *nomad_api.Job, rule *Rule) (int, bool) {
+	if value, ok := job.Meta[HousekeeperKeepLast]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			log.Warnf("could not interpret keep-last for job %s: %s", *job.ID, value)
+			return 0, false
+		}
+		return n, true
+	}
+
+	if rule != nil && rule.KeepLast > 0 {
+		return rule.KeepLast, true
+	}
+
+	return 0, false
+}
+
+// childRetention returns the age beyond which a periodic job's completed
+// children become eligible for GC regardless of keep-last, and whether
+// age-based periodic child GC is opted into at all. The per-job
+// housekeeper/child-retention meta key overrides a matching rule's
+// ChildRetention.
+func childRetention(job *nomad_api.Job, rule *Rule) (time.Duration, bool) {
+	if value, ok := job.Meta[HousekeeperChildRetention]; ok {
+		ttl, err := str2duration.ParseDuration(value)
+		if err != nil {
+			log.Warnf("could not interpret child retention for job %s: %s", *job.ID, value)
+			return 0, false
+		}
+		return ttl, true
+	}
+
+	if rule != nil && rule.ChildRetention != "" {
+		ttl, err := str2duration.ParseDuration(rule.ChildRetention)
+		if err != nil {
+			log.Warnf("could not interpret rule child retention for job %s: %s", *job.ID, rule.ChildRetention)
+			return 0, false
+		}
+		return ttl, true
+	}
+
+	return 0, false
+}
+
+// allocFinishedAt returns the time an allocation's tasks most recently
+// finished, falling back to its ModifyTime if no task reports one.
+func allocFinishedAt(alloc *nomad_api.AllocationListStub) (time.Time, bool) {
+	var latest time.Time
+	found := false
+
+	for _, taskState := range alloc.TaskStates {
+		if taskState.FinishedAt.IsZero() {
+			continue
+		}
+		if !found || taskState.FinishedAt.After(latest) {
+			latest = taskState.FinishedAt
+			found = true
+		}
+	}
+
+	if found {
+		return latest, true
+	}
+
+	if alloc.ModifyTime > 0 {
+		return time.Unix(0, alloc.ModifyTime), true
+	}
+
+	return time.Time{}, false
+}
+
+// lastAllocFinishedAt returns the most recent finish time among a set of
+// allocations. It looks at when allocations actually finished rather
+// than job.SubmitTime, since a batch job's relevance lies in when its
+// work last completed, not when it was first dispatched.
+func lastAllocFinishedAt(allocs []*nomad_api.AllocationListStub) (time.Time, bool) {
+	var latest time.Time
+	found := false
+
+	for _, alloc := range allocs {
+		finishedAt, ok := allocFinishedAt(alloc)
+		if !ok {
+			continue
+		}
+		if !found || finishedAt.After(latest) {
+			latest = finishedAt
+			found = true
+		}
+	}
+
+	return latest, found
+}
+
+// batchExpired reports whether a batch job's most recent allocation
+// finished more than its retention window ago.
+func batchExpired(allocs []*nomad_api.AllocationListStub, retention time.Duration) bool {
+	finished, ok := lastAllocFinishedAt(allocs)
+	if !ok {
+		return false
+	}
+
+	return time.Now().After(finished.Add(retention))
+}
+
+// completedPeriodicChildren returns a periodic parent's dead children,
+// newest-first by submit time.
+func completedPeriodicChildren(children []*nomad_api.JobListStub) []*nomad_api.JobListStub {
+	completed := make([]*nomad_api.JobListStub, 0, len(children))
+	for _, child := range children {
+		if child.Status == "dead" {
+			completed = append(completed, child)
+		}
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].SubmitTime > completed[j].SubmitTime
+	})
+
+	return completed
+}
+
+// periodicChildrenToReap returns a periodic parent's completed child
+// instances beyond the newest keep count. Children are ordered
+// newest-first by submit time so the keep count always refers to the
+// most recent instances.
+func periodicChildrenToReap(children []*nomad_api.JobListStub, keep int) []*nomad_api.JobListStub {
+	completed := completedPeriodicChildren(children)
+
+	if keep >= len(completed) {
+		return nil
+	}
+
+	return completed[keep:]
+}
+
+// childExpiredByAge reports whether a periodic child's most recent
+// allocation finished outside the retention window. It reuses the same
+// allocation-finish-time logic as standalone batch job retention, since
+// a periodic child's relevance lies in when its work last completed,
+// not in where keep-last happens to rank it.
+func (h *Housekeeper) childExpiredByAge(jobs *nomad_api.Jobs, child *nomad_api.JobListStub, retention time.Duration, token string) bool {
+	allocs, _, err := jobs.Allocations(child.ID, false, &nomad_api.QueryOptions{
+		Namespace: child.Namespace,
+		AuthToken: token,
+	})
+	if err != nil {
+		log.Warnf("could not list allocations for periodic child %s: %s", child.ID, err)
+		return false
+	}
+
+	return batchExpired(allocs, retention)
+}
+
+// reapBatchJob deregisters and purges a standalone batch job whose most
+// recent allocation finished outside its retention window.
+func (h *Housekeeper) reapBatchJob(jobs *nomad_api.Jobs, job *nomad_api.Job, retention time.Duration, token string, plan *[]PlanEntry) {
+	allocs, _, err := jobs.Allocations(*job.ID, false, &nomad_api.QueryOptions{
+		Namespace: *job.Namespace,
+		AuthToken: token,
+	})
+	if err != nil {
+		log.Warnf("could not list allocations for batch job %s: %s", *job.ID, err)
+		return
+	}
+
+	if !batchExpired(allocs, retention) {
+		log.Debugf("Batch job %s not past its retention window", *job.ID)
+		h.forgetGrace(*job.Namespace, *job.ID)
+		return
+	}
+
+	jobsExpired.Inc()
+
+	if !h.planOrNotify(job, true, "batch-retention", retention.String(), plan) {
+		return
+	}
+
+	log.Debugf("Reaping batch job %s", *job.ID)
+	if err := deregisterJob(jobs, *job.ID, *job.Namespace, token, true); err != nil {
+		log.Warnf("could not reap batch job %s: %s", *job.ID, err)
+	}
+}
+
+// reapPeriodicChildren deregisters and purges a periodic parent's
+// completed child instances beyond the configured keep-last count, the
+// configured child-retention age, or both. Children only carry the
+// fields on JobListStub, not Meta, so they can't hold their own
+// housekeeper/grace override; instead they inherit the parent job's
+// grace period and go through the same planOrNotify wait as every
+// other reaping path before being deregistered.
+func (h *Housekeeper) reapPeriodicChildren(jobs *nomad_api.Jobs, job *nomad_api.Job, rule *Rule, children []*nomad_api.JobListStub, token string, plan *[]PlanEntry) {
+	keep, keepOk := keepLast(job, rule)
+	retention, retentionOk := childRetention(job, rule)
+	if !keepOk && !retentionOk {
+		for _, child := range children {
+			h.forgetGrace(child.Namespace, child.ID)
+		}
+		return
+	}
+
+	grace := jobGrace(job)
+	reaped := make(map[string]struct{})
+	reap := func(child *nomad_api.JobListStub, reason, source string) {
+		if _, already := reaped[child.ID]; already {
+			return
+		}
+		reaped[child.ID] = struct{}{}
+		jobsExpired.Inc()
+
+		entry := PlanEntry{
+			Job:       child.ID,
+			Namespace: child.Namespace,
+			Reason:    reason,
+			Source:    source,
+			Purge:     true,
+		}
+
+		if !h.planOrNotifyEntry(entry, grace, plan) {
+			return
+		}
+
+		log.Debugf("Reaping periodic child %s", child.ID)
+		if err := deregisterJob(jobs, child.ID, child.Namespace, token, true); err != nil {
+			log.Warnf("could not reap periodic child %s: %s", child.ID, err)
+		}
+	}
+
+	completed := completedPeriodicChildren(children)
+
+	if keepOk {
+		toReap := periodicChildrenToReap(children, keep)
+		kept := make(map[string]struct{}, len(toReap))
+		for _, child := range toReap {
+			kept[child.ID] = struct{}{}
+			reap(child, "periodic-keep-last", fmt.Sprintf("keep=%d", keep))
+		}
+		for _, child := range completed {
+			if _, overKeep := kept[child.ID]; !overKeep {
+				h.forgetGrace(child.Namespace, child.ID)
+			}
+		}
+	}
+
+	if retentionOk {
+		for _, child := range completed {
+			if _, alreadyReaped := reaped[child.ID]; alreadyReaped {
+				continue
+			}
+			if h.childExpiredByAge(jobs, child, retention, token) {
+				reap(child, "periodic-child-retention", retention.String())
+			} else {
+				h.forgetGrace(child.Namespace, child.ID)
+			}
+		}
+	}
+}
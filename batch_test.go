@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	nomad_api "github.com/hashicorp/nomad/api"
+)
+
+func TestKeepLastJobMetaOverridesRule(t *testing.T) {
+	job := &nomad_api.Job{
+		ID:   strPtr("parent"),
+		Meta: map[string]string{HousekeeperKeepLast: "2"},
+	}
+	rule := &Rule{KeepLast: 5}
+
+	n, ok := keepLast(job, rule)
+	if !ok || n != 2 {
+		t.Fatalf("expected job meta (2) to override rule (5), got n=%d ok=%v", n, ok)
+	}
+}
+
+func TestKeepLastFallsBackToRule(t *testing.T) {
+	job := &nomad_api.Job{ID: strPtr("parent")}
+	rule := &Rule{KeepLast: 3}
+
+	n, ok := keepLast(job, rule)
+	if !ok || n != 3 {
+		t.Fatalf("expected rule keep-last of 3, got n=%d ok=%v", n, ok)
+	}
+}
+
+func TestKeepLastNotOptedIn(t *testing.T) {
+	job := &nomad_api.Job{ID: strPtr("parent")}
+
+	if _, ok := keepLast(job, nil); ok {
+		t.Fatalf("expected periodic child GC not to be opted into without meta or rule")
+	}
+}
+
+func TestPeriodicChildrenToReapKeepsNewest(t *testing.T) {
+	children := []*nomad_api.JobListStub{
+		{ID: "child-1", Status: "dead", SubmitTime: 1},
+		{ID: "child-2", Status: "dead", SubmitTime: 3},
+		{ID: "child-3", Status: "dead", SubmitTime: 2},
+		{ID: "child-running", Status: "running", SubmitTime: 4},
+	}
+
+	reap := periodicChildrenToReap(children, 1)
+	if len(reap) != 2 {
+		t.Fatalf("expected 2 children beyond the keep count, got %d", len(reap))
+	}
+	if reap[0].ID != "child-3" || reap[1].ID != "child-1" {
+		t.Fatalf("expected the two oldest completed children in newest-first order, got %v", reap)
+	}
+}
+
+func TestPeriodicChildrenToReapKeepAllWhenFewerThanKeep(t *testing.T) {
+	children := []*nomad_api.JobListStub{
+		{ID: "child-1", Status: "dead", SubmitTime: 1},
+	}
+
+	if reap := periodicChildrenToReap(children, 5); reap != nil {
+		t.Fatalf("expected nothing to reap when fewer completed children than keep count, got %v", reap)
+	}
+}
+
+func TestAllocFinishedAtPrefersTaskStateOverModifyTime(t *testing.T) {
+	finished := time.Unix(1000, 0)
+	alloc := &nomad_api.AllocationListStub{
+		TaskStates: map[string]*nomad_api.TaskState{
+			"task": {FinishedAt: finished},
+		},
+		ModifyTime: time.Unix(2000, 0).UnixNano(),
+	}
+
+	got, ok := allocFinishedAt(alloc)
+	if !ok || !got.Equal(finished) {
+		t.Fatalf("expected task state finish time to win, got %v ok=%v", got, ok)
+	}
+}
+
+func TestAllocFinishedAtFallsBackToModifyTime(t *testing.T) {
+	modifyTime := time.Unix(2000, 0)
+	alloc := &nomad_api.AllocationListStub{
+		ModifyTime: modifyTime.UnixNano(),
+	}
+
+	got, ok := allocFinishedAt(alloc)
+	if !ok || !got.Equal(modifyTime) {
+		t.Fatalf("expected fallback to ModifyTime, got %v ok=%v", got, ok)
+	}
+}
+
+func TestChildRetentionJobMetaOverridesRule(t *testing.T) {
+	job := &nomad_api.Job{
+		ID:   strPtr("parent"),
+		Meta: map[string]string{HousekeeperChildRetention: "1h"},
+	}
+	rule := &Rule{ChildRetention: "24h"}
+
+	d, ok := childRetention(job, rule)
+	if !ok || d != time.Hour {
+		t.Fatalf("expected job meta (1h) to override rule (24h), got d=%s ok=%v", d, ok)
+	}
+}
+
+func TestChildRetentionFallsBackToRule(t *testing.T) {
+	job := &nomad_api.Job{ID: strPtr("parent")}
+	rule := &Rule{ChildRetention: "24h"}
+
+	d, ok := childRetention(job, rule)
+	if !ok || d != 24*time.Hour {
+		t.Fatalf("expected rule child retention of 24h, got d=%s ok=%v", d, ok)
+	}
+}
+
+func TestChildRetentionNotOptedIn(t *testing.T) {
+	job := &nomad_api.Job{ID: strPtr("parent")}
+
+	if _, ok := childRetention(job, nil); ok {
+		t.Fatalf("expected age-based periodic child GC not to be opted into without meta or rule")
+	}
+}
+
+func TestBatchExpired(t *testing.T) {
+	allocs := []*nomad_api.AllocationListStub{
+		{ModifyTime: time.Now().Add(-2 * time.Hour).UnixNano()},
+	}
+
+	if !batchExpired(allocs, time.Hour) {
+		t.Fatalf("expected a job finished 2h ago to be expired past a 1h retention")
+	}
+	if batchExpired(allocs, 3*time.Hour) {
+		t.Fatalf("expected a job finished 2h ago not to be expired within a 3h retention")
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,26 +16,175 @@ import (
 	"github.com/gin-gonic/gin"
 	nomad_api "github.com/hashicorp/nomad/api"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/writer"
 	str2duration "github.com/xhit/go-str2duration/v2"
 )
 
 type Housekeeper struct {
-	NomadClient *nomad_api.Client
+	// Clients holds one Nomad client per configured region, keyed by
+	// region name ("" for the client's own default region). Rebuilt by
+	// reload alongside Config/Rules, so always go through clients()
+	// rather than reading this field directly.
+	Clients map[string]*nomad_api.Client
+	// NamespaceTokens holds the ACL token to use per namespace, keyed by
+	// namespace name. When no per-namespace tokens are configured, it
+	// holds a single nomad_api.AllNamespacesNamespace entry so cleanup
+	// falls back to one cross-namespace query per region using the
+	// client's own NOMAD_TOKEN. Rebuilt by reload; go through
+	// namespaceTokens() rather than reading this field directly.
+	NamespaceTokens map[string]string
+
+	configMu sync.RWMutex
+	Config   Config
+	Rules    *RulesConfig
+
+	mu           sync.Mutex
+	lastSuccess  time.Time
+	pendingGrace map[string]time.Time
+}
+
+// config returns a copy of the housekeeper's current config. It's
+// safe to call concurrently with reload.
+func (h *Housekeeper) config() Config {
+	h.configMu.RLock()
+	defer h.configMu.RUnlock()
+	return h.Config
+}
+
+// rules returns the housekeeper's current rule set. It's safe to call
+// concurrently with reload.
+func (h *Housekeeper) rules() *RulesConfig {
+	h.configMu.RLock()
+	defer h.configMu.RUnlock()
+	return h.Rules
+}
+
+// clients returns the housekeeper's current per-region Nomad clients.
+// It's safe to call concurrently with reload.
+func (h *Housekeeper) clients() map[string]*nomad_api.Client {
+	h.configMu.RLock()
+	defer h.configMu.RUnlock()
+	return h.Clients
+}
+
+// namespaceTokens returns the housekeeper's current per-namespace ACL
+// tokens. It's safe to call concurrently with reload.
+func (h *Housekeeper) namespaceTokens() map[string]string {
+	h.configMu.RLock()
+	defer h.configMu.RUnlock()
+	return h.NamespaceTokens
+}
+
+// reload re-reads the HOUSEKEEPER_* environment and the rules file (in
+// response to SIGHUP) and swaps them in atomically, rebuilding the
+// per-region clients and per-namespace tokens to match so a rotated
+// HOUSEKEEPER_TOKENS or HOUSEKEEPER_REGIONS takes effect immediately.
+// It leaves the running config untouched if any part fails to parse or
+// build.
+func (h *Housekeeper) reload() error {
+	var cfg Config
+	if err := envconfig.Process("housekeeper", &cfg); err != nil {
+		return fmt.Errorf("could not reload config: %s", err)
+	}
+
+	rules, err := LoadRules(cfg.RulesFile)
+	if err != nil {
+		return fmt.Errorf("could not reload rules file: %s", err)
+	}
+
+	clients, err := buildClients(cfg.Regions)
+	if err != nil {
+		return fmt.Errorf("could not reload nomad clients: %s", err)
+	}
+
+	tokens, err := buildNamespaceTokens(cfg.Tokens)
+	if err != nil {
+		return fmt.Errorf("could not reload HOUSEKEEPER_TOKENS: %s", err)
+	}
+
+	h.configMu.Lock()
+	h.Config = cfg
+	h.Rules = rules
+	h.Clients = clients
+	h.NamespaceTokens = tokens
+	h.configMu.Unlock()
+
+	return nil
+}
+
+// recordSuccess notes that a cleanup run completed successfully at t.
+func (h *Housekeeper) recordSuccess(t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSuccess = t
+}
+
+// lastSuccessfulRun returns the time of the most recent successful
+// cleanup run, or the zero Time if none has completed yet.
+func (h *Housekeeper) lastSuccessfulRun() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastSuccess
+}
+
+// graceStart returns when key was first notified as pending deregister,
+// and whether it's been notified at all.
+func (h *Housekeeper) graceStart(key string) (time.Time, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.pendingGrace[key]
+	return t, ok
+}
+
+// markGraceStart records that key was just notified as pending deregister.
+func (h *Housekeeper) markGraceStart(key string, t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pendingGrace == nil {
+		h.pendingGrace = make(map[string]time.Time)
+	}
+	h.pendingGrace[key] = t
+}
+
+// clearGrace forgets key, once its grace period has elapsed and it's
+// been deregistered.
+func (h *Housekeeper) clearGrace(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.pendingGrace, key)
+}
+
+// forgetGrace clears any pending grace-period state for namespace/id.
+// It must be called whenever a job stops being a reaping candidate
+// (redeployed, TTL bumped, rules file reloaded to skip it, allocations
+// no longer past retention, ...) before it re-expires for any reason.
+// Otherwise a stale notification timestamp from an earlier cleanup run
+// survives untouched, and a later re-expiry sees it as already past its
+// grace period and deregisters the job immediately with no fresh
+// webhook notice.
+func (h *Housekeeper) forgetGrace(namespace, id string) {
+	h.clearGrace(namespace + "/" + id)
 }
 
 type Config struct {
-	Interval time.Duration `default:"30s"`
-	DryRun   bool          `envconfig:"dry_run"`
-	RunOnce  bool          `envconfig:"once"`
-	Debug    bool          `envconfig:"debug"`
+	Interval       time.Duration `default:"30s"`
+	DryRun         bool          `envconfig:"dry_run"`
+	RunOnce        bool          `envconfig:"once"`
+	Debug          bool          `envconfig:"debug"`
+	RulesFile      string        `envconfig:"rules"`
+	BatchRetention time.Duration `envconfig:"batch_retention" default:"24h"`
+	ReadyWindow    int           `envconfig:"ready_window" default:"3"`
+	MaxRetries     int           `envconfig:"max_retries" default:"3"`
+	RetryBackoff   time.Duration `envconfig:"retry_backoff" default:"5s"`
+	WebhookURL     string        `envconfig:"webhook_url"`
+	WebhookTimeout time.Duration `envconfig:"webhook_timeout" default:"10s"`
+	Tokens         string        `envconfig:"tokens"`
+	Regions        []string      `envconfig:"regions"`
 }
 
-var (
-	housekeeper Housekeeper
-	config      Config
-)
+var housekeeper Housekeeper
 
 const (
 	HousekeeperTTL     = "housekeeper/ttl"
@@ -41,6 +192,11 @@ const (
 	HousekeeperPurge   = "housekeeper/purge"
 )
 
+// runShutdownTimeout bounds how long shutdown waits for an in-flight
+// cleanup run to notice ctx cancellation and return, so a slow Nomad API
+// call or a misbehaving webhook can't turn a signal into a hang.
+const runShutdownTimeout = 10 * time.Second
+
 func init() {
 	log.SetOutput(io.Discard) // Send all logs to nowhere by default
 
@@ -63,32 +219,82 @@ func init() {
 
 	log.SetFormatter(&log.JSONFormatter{})
 
-	// DefaultConfig gets the NOMAD_ADDR and NOMAD_TOKEN env variables itself
-	nomadClientConfig := nomad_api.DefaultConfig()
-	nomadClient, err := nomad_api.NewClient(nomadClientConfig)
+	var config Config
+	err := envconfig.Process("housekeeper", &config)
 	if err != nil {
-		log.Fatalf("Could not initialize nomad client: %s", err)
+		log.Fatalf("Could not initialize config: %s", err)
 	}
+	housekeeper.Config = config
 
-	housekeeper.NomadClient = nomadClient
+	if config.Debug {
+		log.SetLevel(log.DebugLevel)
+	}
 
-	err = envconfig.Process("housekeeper", &config)
+	clients, err := buildClients(config.Regions)
 	if err != nil {
-		log.Fatalf("Could not initialize config: %s", err)
+		log.Fatalf("Could not initialize nomad clients: %s", err)
 	}
+	housekeeper.Clients = clients
 
-	if config.Debug {
-		log.SetLevel(log.DebugLevel)
+	tokens, err := buildNamespaceTokens(config.Tokens)
+	if err != nil {
+		log.Fatalf("Could not parse HOUSEKEEPER_TOKENS: %s", err)
 	}
+	housekeeper.NamespaceTokens = tokens
+
+	rules, err := LoadRules(config.RulesFile)
+	if err != nil {
+		log.Fatalf("Could not load rules file: %s", err)
+	}
+	housekeeper.Rules = rules
+}
+
+// buildClients creates one Nomad client per configured region. An empty
+// regions list means "whatever NOMAD_REGION/the client default is".
+func buildClients(regions []string) (map[string]*nomad_api.Client, error) {
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+
+	clients := make(map[string]*nomad_api.Client, len(regions))
+	for _, region := range regions {
+		// DefaultConfig gets the NOMAD_ADDR and NOMAD_TOKEN env variables itself
+		nomadClientConfig := nomad_api.DefaultConfig()
+		if region != "" {
+			nomadClientConfig.Region = region
+		}
+
+		client, err := nomad_api.NewClient(nomadClientConfig)
+		if err != nil {
+			return nil, fmt.Errorf("could not initialize nomad client for region %q: %s", region, err)
+		}
+		clients[region] = client
+	}
+
+	return clients, nil
+}
+
+// buildNamespaceTokens parses HOUSEKEEPER_TOKENS into a per-namespace
+// token map, falling back to a single nomad_api.AllNamespacesNamespace
+// entry when none are configured so cleanup falls back to one
+// cross-namespace query per region using the client's own NOMAD_TOKEN.
+func buildNamespaceTokens(raw string) (map[string]string, error) {
+	tokens, err := parseTokens(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		tokens = map[string]string{nomad_api.AllNamespacesNamespace: ""}
+	}
+	return tokens, nil
 }
 
 func main() {
 	c := make(chan os.Signal, 2)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
-	if config.RunOnce {
-		err := cleanup()
-		if err != nil {
+	if housekeeper.config().RunOnce {
+		if err := housekeeper.cleanupWithRetry(); err != nil {
 			log.Fatal(err)
 		}
 		os.Exit(0)
@@ -96,87 +302,297 @@ func main() {
 
 	router := gin.New()
 	router.Use(gin.Recovery())
-	if !config.Debug {
+	if !housekeeper.config().Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// /health is a liveness probe: it only reports that the process is up.
 	router.GET("/health", func(ctx *gin.Context) {
-		log.Info("Received health check")
-		_, err := housekeeper.NomadClient.Status().Leader()
-		if err != nil {
-			ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": "Can't connect to Nomad API"})
+		log.Debug("Received liveness check")
+		ctx.JSON(http.StatusOK, gin.H{"message": "ALIVE"})
+	})
+
+	// /health/ready is a readiness probe: it additionally requires a
+	// reachable Nomad leader and a recent successful cleanup run.
+	router.GET("/health/ready", func(ctx *gin.Context) {
+		log.Debug("Received readiness check")
+
+		for region, client := range housekeeper.clients() {
+			if _, err := client.Status().Leader(); err != nil {
+				ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": fmt.Sprintf("Can't connect to Nomad API in region %q", region)})
+				return
+			}
+		}
+
+		cfg := housekeeper.config()
+		staleAfter := time.Duration(cfg.ReadyWindow) * cfg.Interval
+		if last := housekeeper.lastSuccessfulRun(); last.IsZero() || time.Since(last) > staleAfter {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": "Last cleanup run is missing or stale"})
 			return
 		}
-		ctx.JSON(http.StatusOK, gin.H{"message": "ALL GOOD"})
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "READY"})
 	})
 
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	server := &http.Server{
+		Addr:    "0.0.0.0:8080",
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, cancelRun := context.WithCancel(context.Background())
+	runDone := make(chan struct{})
 	go func() {
-		log.Fatal(router.Run("0.0.0.0:8080"))
+		defer close(runDone)
+		housekeeper.Run(ctx)
 	}()
 
+	for sig := range c {
+		if sig == syscall.SIGHUP {
+			log.Info("SIGHUP received, reloading config and rules file")
+			if err := housekeeper.reload(); err != nil {
+				log.Errorf("could not reload: %s", err)
+			}
+			continue
+		}
+
+		log.Info("Signal received, gracefully shutting down")
+		cancelRun()
+
+		select {
+		case <-runDone:
+		case <-time.After(runShutdownTimeout):
+			log.Warn("cleanup run did not stop in time, shutting down anyway")
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warnf("could not shut down HTTP server cleanly: %s", err)
+		}
+		return
+	}
+}
+
+// Run executes the periodic cleanup loop until ctx is cancelled. It's a
+// method on Housekeeper rather than a loop over package-level globals so
+// tests can construct their own Housekeeper (with a fake Config and
+// Nomad clients) and exercise the loop in isolation.
+func (h *Housekeeper) Run(ctx context.Context) {
 	for {
 		select {
-		case <-c:
-			log.Info("Signal received, gracefully shutting down")
+		case <-ctx.Done():
 			return
-		case <-time.After(config.Interval):
-			err := cleanup()
-			if err != nil {
-				log.Fatal(err)
+		case <-time.After(h.config().Interval):
+			if err := h.cleanupWithRetry(); err != nil {
+				log.Errorf("cleanup failed after %d attempts: %s", h.config().maxRetries(), err)
 			}
 		}
 	}
 }
 
-func cleanup() (err error) {
-	jobs := housekeeper.NomadClient.Jobs()
-	all_jobs, _, err := jobs.List(&nomad_api.QueryOptions{
-		Namespace:  nomad_api.AllNamespacesNamespace,
-		AllowStale: true,
-	})
-	if err != nil {
-		return fmt.Errorf("could not list jobs running on cluster: %s", err)
+// maxRetries returns the configured MaxRetries, clamped to a minimum of
+// 1. A misconfigured HOUSEKEEPER_MAX_RETRIES=0 would otherwise skip the
+// cleanup loop body entirely and return a silent success, leaving the
+// housekeeper looking healthy while never actually reaping anything.
+func (cfg Config) maxRetries() int {
+	if cfg.MaxRetries < 1 {
+		return 1
 	}
+	return cfg.MaxRetries
+}
 
-	for _, current_job := range all_jobs {
-		job, _, err := jobs.Info(current_job.Name, &nomad_api.QueryOptions{
-			Namespace: current_job.Namespace,
-		})
-		if err != nil {
-			log.Errorf("could not get details for job %s : %s", current_job.Name, err)
-			continue
+// cleanupWithRetry runs cleanup, retrying with a linear backoff on
+// failure so a transient Nomad outage doesn't take the process down.
+// It gives up and returns the last error once the configured MaxRetries
+// is exhausted.
+func (h *Housekeeper) cleanupWithRetry() (err error) {
+	cfg := h.config()
+	maxRetries := cfg.maxRetries()
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		err = h.cleanup()
+		if err == nil {
+			return nil
 		}
 
-		log.Debugf("Looking at %s", *job.Name)
+		cleanupFailures.Inc()
+		log.Warnf("cleanup attempt %d/%d failed: %s", attempt, maxRetries, err)
 
-		if shouldSkip(job) {
-			log.Debugf("Skipping %s", *job.Name)
-			continue
+		if attempt < maxRetries {
+			time.Sleep(cfg.RetryBackoff * time.Duration(attempt))
 		}
+	}
+	return err
+}
 
-		if !expired(job) {
-			log.Debugf("Job %s not expired or set up for cleanup", current_job.Name)
-			continue
-		}
+// cleanup scans every configured region and namespace/token pair for
+// jobs to reap. A region or namespace the configured token can't access
+// is logged and skipped rather than failing the whole run; cleanup only
+// errors out if every single one of them failed.
+func (h *Housekeeper) cleanup() (err error) {
+	start := time.Now()
+	defer func() {
+		cleanupDuration.Observe(time.Since(start).Seconds())
+	}()
 
-		if config.DryRun {
-			log.Infof("Would have stopped %s", current_job.Name)
-			continue
-		}
+	cfg := h.config()
+	rules := h.rules()
 
-		log.Debugf("Stopping %s", *job.Name)
-		_, _, err = jobs.Deregister(current_job.ID, shouldPurge(job), &nomad_api.WriteOptions{
-			Namespace: current_job.Namespace,
-		})
+	var plan []PlanEntry
+	seenNamespaces := make(map[string]struct{})
+	attempted, succeeded := 0, 0
 
-		if err != nil {
-			log.Warnf("could not remove job %s: %s", current_job.Name, err)
+	for region, client := range h.clients() {
+		jobs := client.Jobs()
+
+		for namespace, token := range h.namespaceTokens() {
+			attempted++
+
+			all_jobs, _, err := jobs.List(&nomad_api.QueryOptions{
+				Namespace:  namespace,
+				AllowStale: true,
+				AuthToken:  token,
+			})
+			if err != nil {
+				log.Warnf("could not list jobs in region %q namespace %q, skipping: %s", region, namespace, err)
+				continue
+			}
+			succeeded++
+
+			childrenByParent := make(map[string][]*nomad_api.JobListStub)
+			for _, stub := range all_jobs {
+				if stub.ParentID != "" {
+					childrenByParent[stub.ParentID] = append(childrenByParent[stub.ParentID], stub)
+				}
+			}
+
+			for _, current_job := range all_jobs {
+				job, _, err := jobs.Info(current_job.Name, &nomad_api.QueryOptions{
+					Namespace: current_job.Namespace,
+					AuthToken: token,
+				})
+				if err != nil {
+					log.Errorf("could not get details for job %s : %s", current_job.Name, err)
+					continue
+				}
+
+				log.Debugf("Looking at %s", *job.Name)
+				jobsScanned.Inc()
+				seenNamespaces[current_job.Namespace] = struct{}{}
+
+				if *job.ParentID != "" {
+					// Periodic children are only ever reaped through their
+					// parent's reapPeriodicChildren call, against the
+					// parent's matched rule. Skip them here so shouldSkip's
+					// blanket "batch type" check below doesn't forget grace
+					// state reapPeriodicChildren just set for this same
+					// child earlier in this very cleanup() pass.
+					continue
+				}
+
+				rule := rules.match(job)
+
+				if rule != nil && rule.Skip {
+					log.Debugf("Skipping %s", *job.Name)
+					h.forgetGrace(*job.Namespace, *job.ID)
+					for _, child := range childrenByParent[*job.ID] {
+						h.forgetGrace(child.Namespace, child.ID)
+					}
+					continue
+				}
+
+				if job.IsPeriodic() {
+					h.reapPeriodicChildren(jobs, job, rule, childrenByParent[*job.ID], token, &plan)
+					continue
+				}
+
+				if *job.Type == nomad_api.JobTypeBatch && *job.ParentID == "" {
+					if retention, ok := h.batchRetention(job, rule); ok {
+						h.reapBatchJob(jobs, job, retention, token, &plan)
+						continue
+					}
+				}
+
+				if shouldSkip(job, rule) {
+					log.Debugf("Skipping %s", *job.Name)
+					h.forgetGrace(*job.Namespace, *job.ID)
+					continue
+				}
+
+				if !expired(job, rule) {
+					log.Debugf("Job %s not expired or set up for cleanup", current_job.Name)
+					h.forgetGrace(*job.Namespace, *job.ID)
+					continue
+				}
+
+				jobsExpired.Inc()
+				purge := shouldPurge(job, rule)
+
+				if !h.planOrNotify(job, purge, "expired", expirationSource(job, rule), &plan) {
+					continue
+				}
+
+				log.Debugf("Stopping %s", *job.Name)
+				if err := deregisterJob(jobs, current_job.ID, current_job.Namespace, token, purge); err != nil {
+					log.Warnf("could not remove job %s: %s", current_job.Name, err)
+				}
+			}
 		}
 	}
+
+	if attempted > 0 && succeeded == 0 {
+		return fmt.Errorf("could not list jobs in any configured region/namespace")
+	}
+
+	if cfg.DryRun {
+		h.emitPlan(plan)
+	}
+
+	now := time.Now()
+	for namespace := range seenNamespaces {
+		lastSuccessfulRun.WithLabelValues(namespace).Set(float64(now.Unix()))
+	}
+	h.recordSuccess(now)
+
+	return nil
+}
+
+// deregisterJob deregisters a job and keeps the cleanup metrics in sync;
+// it's the single place batch/periodic/TTL reaping funnel through so
+// jobs_deregistered, jobs_purged and deregister_errors stay accurate.
+func deregisterJob(jobs *nomad_api.Jobs, jobID, namespace, token string, purge bool) error {
+	_, _, err := jobs.Deregister(jobID, purge, &nomad_api.WriteOptions{
+		Namespace: namespace,
+		AuthToken: token,
+	})
+	if err != nil {
+		deregisterErrors.Inc()
+		return err
+	}
+
+	jobsDeregistered.Inc()
+	if purge {
+		jobsPurged.Inc()
+	}
 	return nil
 }
 
-func shouldSkip(job *nomad_api.Job) bool {
+// shouldSkip reports whether job should be ignored entirely, regardless
+// of TTL/expiration. A matching rule with Skip set always wins; failing
+// that, the hard-coded defaults (not running, batch, periodic) apply.
+func shouldSkip(job *nomad_api.Job, rule *Rule) bool {
+	if rule != nil && rule.Skip {
+		return true
+	}
+
 	if *job.Status != "running" {
 		return true
 	}
@@ -192,7 +608,10 @@ func shouldSkip(job *nomad_api.Job) bool {
 	return false
 }
 
-func shouldPurge(job *nomad_api.Job) bool {
+// shouldPurge reports whether a deregistered job should also be purged
+// from Nomad's state store. The per-job housekeeper/purge meta key
+// always takes precedence over a matching rule.
+func shouldPurge(job *nomad_api.Job, rule *Rule) bool {
 	// Cron or batch jobs are ignored anyway
 	if *job.ParentID != "" {
 		return false
@@ -203,10 +622,18 @@ func shouldPurge(job *nomad_api.Job) bool {
 			return strings.ToLower(value) == "true"
 		}
 	}
+
+	if rule != nil {
+		return rule.Purge
+	}
 	return false
 }
 
-func expired(job *nomad_api.Job) bool {
+// expired reports whether job is past its TTL or expiration date. The
+// per-job housekeeper/ttl and housekeeper/expires meta keys are checked
+// first as overrides; if neither is set, the matching rule (if any)
+// supplies the default policy instead.
+func expired(job *nomad_api.Job, rule *Rule) bool {
 	now := time.Now()
 	for key, value := range job.Meta {
 		if key == HousekeeperTTL {
@@ -215,7 +642,7 @@ func expired(job *nomad_api.Job) bool {
 				log.Warnf("could not interpret ttl for job %s: %s", *job.ID, value)
 				break
 			}
-			jobStart := time.Unix(*job.SubmitTime, 0)
+			jobStart := time.Unix(0, *job.SubmitTime)
 			deadline := jobStart.Add(ttl)
 			return now.After(deadline)
 		}
@@ -228,7 +655,59 @@ func expired(job *nomad_api.Job) bool {
 			return now.After(expiration)
 		}
 	}
+
+	if rule == nil {
+		return false
+	}
+
+	if rule.TTL != "" {
+		ttl, err := str2duration.ParseDuration(rule.TTL)
+		if err != nil {
+			log.Warnf("could not interpret rule ttl for job %s: %s", *job.ID, rule.TTL)
+			return false
+		}
+		jobStart := time.Unix(0, *job.SubmitTime)
+		return now.After(jobStart.Add(ttl))
+	}
+
+	if rule.Expires != "" {
+		expiration, err := dateparse.ParseAny(rule.Expires)
+		if err != nil {
+			log.Warnf("could not interpret rule expiration date (%s) for job %s", rule.Expires, *job.ID)
+			return false
+		}
+		return now.After(expiration)
+	}
+
 	return false
 }
 
-// MAYBE: rules file that ignores tagged jobs but works on the others
+// expirationSource reports which policy made expired(job, rule) return
+// true, for use in dry-run plans and pre-action notifications.
+func expirationSource(job *nomad_api.Job, rule *Rule) string {
+	for key, value := range job.Meta {
+		if key == HousekeeperTTL {
+			if _, err := str2duration.ParseDuration(value); err != nil {
+				break
+			}
+			return "meta:ttl"
+		}
+		if key == HousekeeperExpires {
+			if _, err := dateparse.ParseAny(value); err != nil {
+				break
+			}
+			return "meta:expires"
+		}
+	}
+
+	if rule != nil {
+		if rule.TTL != "" {
+			return "rule:ttl"
+		}
+		if rule.Expires != "" {
+			return "rule:expires"
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nomad_api "github.com/hashicorp/nomad/api"
+)
+
+// fakeNomad is a minimal stand-in for the Nomad HTTP API: just enough of
+// /v1/jobs, /v1/job/{id}, /v1/job/{id}/allocations and deregistration
+// for cleanup() to scan and act on jobs against.
+type fakeNomad struct {
+	mu           sync.Mutex
+	jobs         map[string]*nomad_api.Job
+	allocs       map[string][]*nomad_api.AllocationListStub
+	deregistered []string
+	listFailures int32 // decremented on each /v1/jobs call while > 0
+}
+
+func newFakeNomad() *fakeNomad {
+	return &fakeNomad{
+		jobs:   make(map[string]*nomad_api.Job),
+		allocs: make(map[string][]*nomad_api.AllocationListStub),
+	}
+}
+
+func (f *fakeNomad) addJob(job *nomad_api.Job, allocs []*nomad_api.AllocationListStub) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[*job.ID] = job
+	if allocs != nil {
+		f.allocs[*job.ID] = allocs
+	}
+}
+
+func (f *fakeNomad) wasDeregistered(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.deregistered {
+		if d == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeNomad) server(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&f.listFailures, -1) >= 0 {
+			http.Error(w, "simulated outage", http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt32(&f.listFailures, 1) // undo past-zero decrements once drained
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		stubs := make([]*nomad_api.JobListStub, 0, len(f.jobs))
+		for _, job := range f.jobs {
+			stubs = append(stubs, &nomad_api.JobListStub{
+				ID:         *job.ID,
+				ParentID:   strVal(job.ParentID),
+				Name:       *job.Name,
+				Type:       *job.Type,
+				Namespace:  *job.Namespace,
+				Status:     *job.Status,
+				SubmitTime: *job.SubmitTime,
+			})
+		}
+		_ = json.NewEncoder(w).Encode(stubs)
+	})
+
+	mux.HandleFunc("/v1/job/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/job/")
+
+		if strings.HasSuffix(id, "/allocations") {
+			id = strings.TrimSuffix(id, "/allocations")
+			f.mu.Lock()
+			allocs := f.allocs[id]
+			f.mu.Unlock()
+			_ = json.NewEncoder(w).Encode(allocs)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			f.mu.Lock()
+			job, ok := f.jobs[id]
+			f.mu.Unlock()
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(job)
+		case http.MethodDelete:
+			f.mu.Lock()
+			delete(f.jobs, id)
+			f.deregistered = append(f.deregistered, id)
+			f.mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]string{"EvalID": ""})
+		}
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func strVal(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// newTestHousekeeper builds a Housekeeper whose sole client points at ts,
+// querying every namespace with an empty token, matching the fallback
+// buildNamespaceTokens takes when HOUSEKEEPER_TOKENS is unset.
+func newTestHousekeeper(t *testing.T, ts *httptest.Server, cfg Config) *Housekeeper {
+	t.Helper()
+
+	nomadCfg := nomad_api.DefaultConfig()
+	nomadCfg.Address = ts.URL
+	client, err := nomad_api.NewClient(nomadCfg)
+	if err != nil {
+		t.Fatalf("could not build nomad client: %s", err)
+	}
+
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Millisecond
+	}
+	if cfg.WebhookTimeout == 0 {
+		cfg.WebhookTimeout = time.Second
+	}
+
+	return &Housekeeper{
+		Clients:         map[string]*nomad_api.Client{"": client},
+		NamespaceTokens: map[string]string{nomad_api.AllNamespacesNamespace: ""},
+		Config:          cfg,
+	}
+}
+
+func TestCleanupDeregistersExpiredJob(t *testing.T) {
+	fake := newFakeNomad()
+	submitTime := time.Now().Add(-time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("svc"),
+		Name:       strPtr("svc"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeService),
+		Status:     strPtr("running"),
+		ParentID:   strPtr(""),
+		SubmitTime: &submitTime,
+		Meta:       map[string]string{HousekeeperTTL: "1s"},
+	}, nil)
+
+	h := newTestHousekeeper(t, fake.server(t), Config{})
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !fake.wasDeregistered("svc") {
+		t.Fatalf("expected expired job to be deregistered")
+	}
+	if h.lastSuccessfulRun().IsZero() {
+		t.Fatalf("expected a successful run to be recorded")
+	}
+}
+
+func TestCleanupHonorsGracePeriod(t *testing.T) {
+	fake := newFakeNomad()
+	submitTime := time.Now().Add(-time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("svc"),
+		Name:       strPtr("svc"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeService),
+		Status:     strPtr("running"),
+		ParentID:   strPtr(""),
+		SubmitTime: &submitTime,
+		Meta: map[string]string{
+			HousekeeperTTL:   "1s",
+			HousekeeperGrace: "30ms",
+		},
+	}, nil)
+
+	var webhookCalls int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&webhookCalls, 1)
+	}))
+	defer webhook.Close()
+
+	h := newTestHousekeeper(t, fake.server(t), Config{WebhookURL: webhook.URL})
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.wasDeregistered("svc") {
+		t.Fatalf("expected job not to be deregistered before its grace period elapses")
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 1 {
+		t.Fatalf("expected exactly one pre-action webhook call, got %d", calls)
+	}
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.wasDeregistered("svc") {
+		t.Fatalf("expected job still within its grace period not to be deregistered")
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 1 {
+		t.Fatalf("expected no repeat webhook call while still within the grace period, got %d", calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !fake.wasDeregistered("svc") {
+		t.Fatalf("expected job to be deregistered once its grace period elapsed")
+	}
+}
+
+func TestCleanupForgetsGraceOnceJobNoLongerExpired(t *testing.T) {
+	fake := newFakeNomad()
+	submitTime := time.Now().Add(-time.Hour).UnixNano()
+	job := &nomad_api.Job{
+		ID:         strPtr("svc"),
+		Name:       strPtr("svc"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeService),
+		Status:     strPtr("running"),
+		ParentID:   strPtr(""),
+		SubmitTime: &submitTime,
+		Meta: map[string]string{
+			HousekeeperTTL:   "1s",
+			HousekeeperGrace: "30ms",
+		},
+	}
+	fake.addJob(job, nil)
+
+	var webhookCalls int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&webhookCalls, 1)
+	}))
+	defer webhook.Close()
+
+	h := newTestHousekeeper(t, fake.server(t), Config{WebhookURL: webhook.URL})
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 1 {
+		t.Fatalf("expected the first cleanup to notify once, got %d calls", calls)
+	}
+
+	// The owner redeploys/bumps the TTL before the grace period elapses:
+	// the job is no longer expired.
+	delete(job.Meta, HousekeeperTTL)
+	fake.addJob(job, nil)
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.wasDeregistered("svc") {
+		t.Fatalf("expected job not to be deregistered once it's no longer expired")
+	}
+
+	// Wait out the original grace window, then let the job expire again.
+	time.Sleep(40 * time.Millisecond)
+	job.Meta[HousekeeperTTL] = "1s"
+	fake.addJob(job, nil)
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.wasDeregistered("svc") {
+		t.Fatalf("expected re-expiry to restart the grace period rather than deregister immediately")
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 2 {
+		t.Fatalf("expected a fresh webhook notification on re-expiry, got %d calls total", calls)
+	}
+}
+
+func TestCleanupReapsExpiredStandaloneBatchJob(t *testing.T) {
+	fake := newFakeNomad()
+	submitTime := time.Now().Add(-48 * time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("batch-job"),
+		Name:       strPtr("batch-job"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("dead"),
+		ParentID:   strPtr(""),
+		SubmitTime: &submitTime,
+		Meta:       map[string]string{HousekeeperBatchRetention: "24h"},
+	}, []*nomad_api.AllocationListStub{
+		{ModifyTime: time.Now().Add(-36 * time.Hour).UnixNano()},
+	})
+
+	h := newTestHousekeeper(t, fake.server(t), Config{})
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !fake.wasDeregistered("batch-job") {
+		t.Fatalf("expected a batch job past its retention window to be deregistered")
+	}
+}
+
+func TestCleanupReapsStalePeriodicChildrenBeyondKeepLast(t *testing.T) {
+	fake := newFakeNomad()
+	parentSubmit := time.Now().Add(-72 * time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron"),
+		Name:       strPtr("cron"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("running"),
+		ParentID:   strPtr(""),
+		SubmitTime: &parentSubmit,
+		Periodic:   &nomad_api.PeriodicConfig{},
+		Meta:       map[string]string{HousekeeperKeepLast: "1"},
+	}, nil)
+
+	older := time.Now().Add(-2 * time.Hour).UnixNano()
+	newer := time.Now().Add(-1 * time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron/periodic-older"),
+		Name:       strPtr("cron/periodic-older"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("dead"),
+		ParentID:   strPtr("cron"),
+		SubmitTime: &older,
+	}, nil)
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron/periodic-newer"),
+		Name:       strPtr("cron/periodic-newer"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("dead"),
+		ParentID:   strPtr("cron"),
+		SubmitTime: &newer,
+	}, nil)
+
+	h := newTestHousekeeper(t, fake.server(t), Config{})
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !fake.wasDeregistered("cron/periodic-older") {
+		t.Fatalf("expected the older completed child beyond keep-last to be deregistered")
+	}
+	if fake.wasDeregistered("cron/periodic-newer") {
+		t.Fatalf("expected the newest completed child within keep-last to survive")
+	}
+}
+
+func TestCleanupPeriodicChildRetentionDoesNotClearKeepLastGrace(t *testing.T) {
+	fake := newFakeNomad()
+	parentSubmit := time.Now().Add(-72 * time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron"),
+		Name:       strPtr("cron"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("running"),
+		ParentID:   strPtr(""),
+		SubmitTime: &parentSubmit,
+		Periodic:   &nomad_api.PeriodicConfig{},
+		Meta: map[string]string{
+			HousekeeperKeepLast:       "1",
+			HousekeeperChildRetention: "24h",
+			HousekeeperGrace:          "30ms",
+		},
+	}, nil)
+
+	older := time.Now().Add(-2 * time.Hour).UnixNano()
+	newer := time.Now().Add(-1 * time.Hour).UnixNano()
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron/periodic-older"),
+		Name:       strPtr("cron/periodic-older"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("dead"),
+		ParentID:   strPtr("cron"),
+		SubmitTime: &older,
+	}, []*nomad_api.AllocationListStub{
+		{ModifyTime: time.Now().Add(-time.Hour).UnixNano()},
+	})
+	fake.addJob(&nomad_api.Job{
+		ID:         strPtr("cron/periodic-newer"),
+		Name:       strPtr("cron/periodic-newer"),
+		Namespace:  strPtr("default"),
+		Type:       strPtr(nomad_api.JobTypeBatch),
+		Status:     strPtr("dead"),
+		ParentID:   strPtr("cron"),
+		SubmitTime: &newer,
+	}, nil)
+
+	var webhookCalls int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&webhookCalls, 1)
+	}))
+	defer webhook.Close()
+
+	h := newTestHousekeeper(t, fake.server(t), Config{WebhookURL: webhook.URL})
+
+	// The older child is over keep-last but well within the 24h
+	// child-retention window: keep-last starts its grace period, and
+	// the retention pass over the same completed child must not clear
+	// that just-started grace state.
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fake.wasDeregistered("cron/periodic-older") {
+		t.Fatalf("expected the child to wait out its grace period before being deregistered")
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 1 {
+		t.Fatalf("expected exactly one notification while grace is pending, got %d", calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if err := h.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !fake.wasDeregistered("cron/periodic-older") {
+		t.Fatalf("expected the child to be deregistered once its grace period elapsed")
+	}
+	if calls := atomic.LoadInt32(&webhookCalls); calls != 1 {
+		t.Fatalf("expected no additional notification once grace had already started, got %d calls total", calls)
+	}
+}
+
+func TestCleanupWithRetryRecoversFromTransientFailure(t *testing.T) {
+	fake := newFakeNomad()
+	fake.listFailures = 2
+
+	h := newTestHousekeeper(t, fake.server(t), Config{MaxRetries: 3})
+
+	if err := h.cleanupWithRetry(); err != nil {
+		t.Fatalf("expected cleanup to eventually succeed, got: %s", err)
+	}
+}
+
+func TestCleanupWithRetryZeroMaxRetriesStillRunsOnce(t *testing.T) {
+	fake := newFakeNomad()
+
+	h := newTestHousekeeper(t, fake.server(t), Config{MaxRetries: 0})
+
+	if err := h.cleanupWithRetry(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h.lastSuccessfulRun().IsZero() {
+		t.Fatalf("expected HOUSEKEEPER_MAX_RETRIES=0 to still run cleanup once, not skip it")
+	}
+}
+
+func TestHousekeeperRunExecutesCleanupUntilCancelled(t *testing.T) {
+	fake := newFakeNomad()
+	h := newTestHousekeeper(t, fake.server(t), Config{Interval: 10 * time.Millisecond, MaxRetries: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		h.Run(ctx)
+		close(done)
+	}()
+
+	<-done
+
+	if h.lastSuccessfulRun().IsZero() {
+		t.Fatalf("expected Run to have executed at least one cleanup before ctx was cancelled")
+	}
+}
+
+func TestHousekeeperReloadPicksUpEnvAndRulesFile(t *testing.T) {
+	rulesPath := filepath.Join(t.TempDir(), "rules.yaml")
+	writeFile(t, rulesPath, "rules:\n  - namespace: staging\n    skip: true\n")
+
+	t.Setenv("HOUSEKEEPER_DRY_RUN", "true")
+	t.Setenv("HOUSEKEEPER_RULES", rulesPath)
+
+	h := &Housekeeper{}
+	if err := h.reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !h.config().DryRun {
+		t.Fatalf("expected reload to pick up HOUSEKEEPER_DRY_RUN from the environment")
+	}
+	if len(h.rules().Rules) != 1 || !h.rules().Rules[0].Skip {
+		t.Fatalf("expected reload to load the rules file, got %+v", h.rules().Rules)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	jobsScanned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_jobs_scanned_total",
+		Help: "Total number of jobs inspected across all cleanup runs.",
+	})
+	jobsExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_jobs_expired_total",
+		Help: "Total number of jobs found eligible for cleanup (expired, batch-retained, or stale periodic children).",
+	})
+	jobsDeregistered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_jobs_deregistered_total",
+		Help: "Total number of jobs successfully deregistered.",
+	})
+	jobsPurged = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_jobs_purged_total",
+		Help: "Total number of jobs deregistered with purge=true.",
+	})
+	deregisterErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_deregister_errors_total",
+		Help: "Total number of errors encountered while deregistering jobs.",
+	})
+	cleanupFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "housekeeper_cleanup_failures_total",
+		Help: "Total number of cleanup run attempts that failed outright (e.g. could not list jobs).",
+	})
+	cleanupDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "housekeeper_cleanup_duration_seconds",
+		Help: "Time taken to complete a cleanup run.",
+	})
+	lastSuccessfulRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "housekeeper_last_successful_run_timestamp_seconds",
+		Help: "Unix timestamp of the last cleanup run that completed successfully, per namespace.",
+	}, []string{"namespace"})
+)
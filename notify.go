@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	nomad_api "github.com/hashicorp/nomad/api"
+	log "github.com/sirupsen/logrus"
+	str2duration "github.com/xhit/go-str2duration/v2"
+)
+
+const HousekeeperGrace = "housekeeper/grace"
+
+// PlanEntry describes one job a cleanup run would act on: either a
+// dry-run line item, or the pre-action notice sent before a live
+// Deregister.
+type PlanEntry struct {
+	Job       string `json:"job"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Source    string `json:"source"`
+	Purge     bool   `json:"purge"`
+}
+
+// jobGrace returns how long to wait after notifying a job's owner
+// before it's actually deregistered, from the housekeeper/grace meta
+// key. Zero means deregister right after notifying.
+func jobGrace(job *nomad_api.Job) time.Duration {
+	value, ok := job.Meta[HousekeeperGrace]
+	if !ok {
+		return 0
+	}
+
+	grace, err := str2duration.ParseDuration(value)
+	if err != nil {
+		log.Warnf("could not interpret grace period for job %s: %s", *job.ID, value)
+		return 0
+	}
+	return grace
+}
+
+// postWebhook POSTs entry as JSON to the configured webhook URL. It's a
+// no-op when no webhook is configured; failures are logged rather than
+// propagated, since a notification hiccup shouldn't block cleanup. The
+// request is bounded by the configured WebhookTimeout so a webhook that
+// accepts the connection but never responds can't wedge the whole
+// cleanup run.
+func (h *Housekeeper) postWebhook(entry PlanEntry) {
+	webhookURL := h.config().WebhookURL
+	if webhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Warnf("could not encode webhook payload for job %s: %s", entry.Job, err)
+		return
+	}
+
+	client := &http.Client{Timeout: h.config().WebhookTimeout}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("could not build webhook request for job %s: %s", entry.Job, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Warnf("could not notify webhook for job %s: %s", entry.Job, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Warnf("webhook returned %s for job %s", resp.Status, entry.Job)
+	}
+}
+
+// emitPlan prints the dry-run plan to stdout and, if a webhook is
+// configured, forwards each entry to it too.
+func (h *Housekeeper) emitPlan(plan []PlanEntry) {
+	if plan == nil {
+		plan = []PlanEntry{}
+	}
+
+	encoded, err := json.Marshal(plan)
+	if err != nil {
+		log.Warnf("could not encode dry-run plan: %s", err)
+		return
+	}
+
+	fmt.Println(string(encoded))
+
+	for _, entry := range plan {
+		h.postWebhook(entry)
+	}
+}
+
+// planOrNotify is the decision point every live reaping path (TTL
+// expiry, batch retention) funnels through before calling Deregister.
+// In dry-run mode it appends to plan and reports that the caller
+// shouldn't act. In live mode it notifies the configured webhook and
+// reports whether the job's grace period (if any) has elapsed.
+func (h *Housekeeper) planOrNotify(job *nomad_api.Job, purge bool, reason, source string, plan *[]PlanEntry) (proceed bool) {
+	entry := PlanEntry{
+		Job:       *job.ID,
+		Namespace: *job.Namespace,
+		Reason:    reason,
+		Source:    source,
+		Purge:     purge,
+	}
+
+	return h.planOrNotifyEntry(entry, jobGrace(job), plan)
+}
+
+// planOrNotifyEntry is the grace-aware core planOrNotify funnels
+// through. It's split out so callers that don't have a *nomad_api.Job
+// to hand, such as periodic children which only carry JobListStub
+// fields, can still go through the same dry-run/grace/webhook handling
+// by supplying their own grace period (e.g. the periodic parent's).
+func (h *Housekeeper) planOrNotifyEntry(entry PlanEntry, grace time.Duration, plan *[]PlanEntry) (proceed bool) {
+	if h.config().DryRun {
+		*plan = append(*plan, entry)
+		return false
+	}
+
+	if grace == 0 {
+		h.postWebhook(entry)
+		return true
+	}
+
+	key := entry.Namespace + "/" + entry.Job
+	startedAt, pending := h.graceStart(key)
+	if !pending {
+		log.Debugf("Notifying owner of %s, deregistering in %s", entry.Job, grace)
+		h.postWebhook(entry)
+		h.markGraceStart(key, time.Now())
+		return false
+	}
+
+	if time.Since(startedAt) < grace {
+		log.Debugf("Job %s still within its %s grace period", entry.Job, grace)
+		return false
+	}
+
+	h.clearGrace(key)
+	return true
+}
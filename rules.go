@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	nomad_api "github.com/hashicorp/nomad/api"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a single entry in the rules file: a set of match criteria plus
+// the cleanup policy to apply to jobs that satisfy all of them.
+type Rule struct {
+	Namespace     string            `yaml:"namespace" hcl:"namespace,optional"`
+	Name          string            `yaml:"name" hcl:"name,optional"`
+	Meta          map[string]string `yaml:"meta" hcl:"meta,optional"`
+	TaskGroupMeta map[string]string `yaml:"task_group_meta" hcl:"task_group_meta,optional"`
+	Datacenters   []string          `yaml:"datacenters" hcl:"datacenters,optional"`
+
+	TTL     string `yaml:"ttl" hcl:"ttl,optional"`
+	Expires string `yaml:"expires" hcl:"expires,optional"`
+	Purge   bool   `yaml:"purge" hcl:"purge,optional"`
+	Skip    bool   `yaml:"skip" hcl:"skip,optional"`
+
+	BatchGC        bool   `yaml:"batch_gc" hcl:"batch_gc,optional"`
+	BatchRetention string `yaml:"batch_retention" hcl:"batch_retention,optional"`
+	KeepLast       int    `yaml:"keep_last" hcl:"keep_last,optional"`
+	ChildRetention string `yaml:"child_retention" hcl:"child_retention,optional"`
+}
+
+// RulesConfig is the top-level shape of the file pointed to by
+// HOUSEKEEPER_RULES. Rules are evaluated in the order they're declared
+// and the first one that matches a job wins.
+type RulesConfig struct {
+	Rules []Rule `yaml:"rules" hcl:"rule,block"`
+}
+
+// LoadRules reads and parses the rules file at filePath. Only ".hcl"
+// parses as HCL, one `rule` block per entry; every other extension
+// (including none) parses as YAML, as it always has. An empty filePath
+// isn't an error, it just means no rules are configured.
+func LoadRules(filePath string) (*RulesConfig, error) {
+	if filePath == "" {
+		return &RulesConfig{}, nil
+	}
+
+	var rules RulesConfig
+
+	if strings.HasSuffix(filePath, ".hcl") {
+		if err := hclsimple.DecodeFile(filePath, nil, &rules); err != nil {
+			return nil, fmt.Errorf("could not parse rules file %s: %s", filePath, err)
+		}
+		return &rules, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rules file %s: %s", filePath, err)
+	}
+
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("could not parse rules file %s: %s", filePath, err)
+	}
+
+	return &rules, nil
+}
+
+// match returns the first rule that applies to job, or nil if none do.
+func (rc *RulesConfig) match(job *nomad_api.Job) *Rule {
+	if rc == nil {
+		return nil
+	}
+
+	for i := range rc.Rules {
+		if rc.Rules[i].matches(job) {
+			return &rc.Rules[i]
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(job *nomad_api.Job) bool {
+	if r.Namespace != "" && r.Namespace != *job.Namespace {
+		return false
+	}
+
+	if r.Name != "" {
+		ok, err := path.Match(r.Name, *job.Name)
+		if err != nil {
+			log.Warnf("invalid name glob %q in rules file: %s", r.Name, err)
+			return false
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	for key, value := range r.Meta {
+		if job.Meta[key] != value {
+			return false
+		}
+	}
+
+	if len(r.TaskGroupMeta) > 0 && !anyTaskGroupMatches(job, r.TaskGroupMeta) {
+		return false
+	}
+
+	if len(r.Datacenters) > 0 && !intersects(r.Datacenters, job.Datacenters) {
+		return false
+	}
+
+	return true
+}
+
+func anyTaskGroupMatches(job *nomad_api.Job, want map[string]string) bool {
+	for _, group := range job.TaskGroups {
+		matches := true
+		for key, value := range want {
+			if group.Meta[key] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
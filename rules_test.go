@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	nomad_api "github.com/hashicorp/nomad/api"
+)
+
+func testJob(namespace, name string, meta map[string]string, datacenters []string) *nomad_api.Job {
+	return &nomad_api.Job{
+		Namespace:   &namespace,
+		Name:        &name,
+		Meta:        meta,
+		Datacenters: datacenters,
+	}
+}
+
+func TestRulesConfigMatchFirstWins(t *testing.T) {
+	rules := &RulesConfig{
+		Rules: []Rule{
+			{Namespace: "staging", Skip: true},
+			{Name: "web-*", Purge: true},
+		},
+	}
+
+	job := testJob("staging", "web-frontend", nil, nil)
+
+	rule := rules.match(job)
+	if rule == nil || !rule.Skip {
+		t.Fatalf("expected the first matching rule (staging, skip) to win, got %+v", rule)
+	}
+}
+
+func TestRulesConfigMatchNoneMatch(t *testing.T) {
+	rules := &RulesConfig{
+		Rules: []Rule{
+			{Namespace: "staging"},
+		},
+	}
+
+	job := testJob("production", "web-frontend", nil, nil)
+
+	if rule := rules.match(job); rule != nil {
+		t.Fatalf("expected no match, got %+v", rule)
+	}
+}
+
+func TestRuleMatchesMeta(t *testing.T) {
+	rule := Rule{Meta: map[string]string{"team": "payments"}}
+
+	matching := testJob("default", "billing", map[string]string{"team": "payments"}, nil)
+	if !rule.matches(matching) {
+		t.Fatalf("expected rule to match job with matching meta")
+	}
+
+	mismatching := testJob("default", "billing", map[string]string{"team": "infra"}, nil)
+	if rule.matches(mismatching) {
+		t.Fatalf("expected rule not to match job with different meta")
+	}
+}
+
+func TestRuleMatchesNameGlob(t *testing.T) {
+	rule := Rule{Name: "cron-*"}
+
+	if !rule.matches(testJob("default", "cron-nightly", nil, nil)) {
+		t.Fatalf("expected glob to match cron-nightly")
+	}
+	if rule.matches(testJob("default", "web-frontend", nil, nil)) {
+		t.Fatalf("expected glob not to match web-frontend")
+	}
+}
+
+func TestRuleMatchesDatacenters(t *testing.T) {
+	rule := Rule{Datacenters: []string{"dc1", "dc2"}}
+
+	if !rule.matches(testJob("default", "job", nil, []string{"dc2", "dc3"})) {
+		t.Fatalf("expected overlapping datacenters to match")
+	}
+	if rule.matches(testJob("default", "job", nil, []string{"dc3"})) {
+		t.Fatalf("expected disjoint datacenters not to match")
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	if !intersects([]string{"a", "b"}, []string{"b", "c"}) {
+		t.Fatalf("expected overlapping slices to intersect")
+	}
+	if intersects([]string{"a"}, []string{"b"}) {
+		t.Fatalf("expected disjoint slices not to intersect")
+	}
+}
+
+func TestLoadRulesEmptyPath(t *testing.T) {
+	rules, err := LoadRules("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rules.Rules) != 0 {
+		t.Fatalf("expected no rules for an empty path, got %+v", rules.Rules)
+	}
+}
+
+func TestLoadRulesYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeFile(t, path, `
+rules:
+  - namespace: staging
+    skip: true
+  - name: "web-*"
+    ttl: 24h
+    purge: true
+`)
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rules.Rules) != 2 || !rules.Rules[0].Skip || rules.Rules[1].TTL != "24h" {
+		t.Fatalf("unexpected parsed rules: %+v", rules.Rules)
+	}
+}
+
+func TestLoadRulesHCL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.hcl")
+	writeFile(t, path, `
+rule {
+  namespace = "staging"
+  skip      = true
+}
+
+rule {
+  name  = "web-*"
+  ttl   = "24h"
+  purge = true
+}
+`)
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rules.Rules) != 2 || !rules.Rules[0].Skip || rules.Rules[1].TTL != "24h" {
+		t.Fatalf("unexpected parsed rules: %+v", rules.Rules)
+	}
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("could not write %s: %s", path, err)
+	}
+}
+
+func TestAnyTaskGroupMatches(t *testing.T) {
+	job := &nomad_api.Job{
+		TaskGroups: []*nomad_api.TaskGroup{
+			{Meta: map[string]string{"tier": "backend"}},
+			{Meta: map[string]string{"tier": "frontend"}},
+		},
+	}
+
+	if !anyTaskGroupMatches(job, map[string]string{"tier": "frontend"}) {
+		t.Fatalf("expected a task group with the wanted meta to match")
+	}
+	if anyTaskGroupMatches(job, map[string]string{"tier": "database"}) {
+		t.Fatalf("expected no task group to match an unused value")
+	}
+}